@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const defaultAPIAddr = "127.0.0.1:8618"
+
+// healthzThreshold is how stale the last successful probe may be before
+// /healthz reports unhealthy.
+const healthzThreshold = 30 * time.Second
+
+// apiServer exposes stats and the query log over HTTP for external
+// scraping (dashboards, Prometheus, curl).
+type apiServer struct {
+	stats *Stats
+	qlog  *querylog
+	mux   *http.ServeMux
+}
+
+func newAPIServer(stats *Stats, qlog *querylog) *apiServer {
+	s := &apiServer{stats: stats, qlog: qlog, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/stats", s.handleStats)
+	s.mux.HandleFunc("/querylog", s.handleQuerylog)
+	s.mux.HandleFunc("/healthz", s.handleHealthz)
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	return s
+}
+
+// apiAddrFromEnv returns the bind address for the HTTP API, read from
+// DNS_API_ADDR, defaulting to defaultAPIAddr.
+func apiAddrFromEnv() string {
+	if addr := os.Getenv("DNS_API_ADDR"); addr != "" {
+		return addr
+	}
+	return defaultAPIAddr
+}
+
+// serve starts the HTTP API listening on addr. It's meant to be run in its
+// own goroutine; a failure to bind is logged and the probe loop continues
+// without the API rather than crashing the monitor.
+func (s *apiServer) serve(addr string) {
+	if err := http.ListenAndServe(addr, s.mux); err != nil {
+		fmt.Printf("⚠️  HTTP API failed to start on %s: %v\n", addr, err)
+	}
+}
+
+type statsResponse struct {
+	SuccessCount  int       `json:"success_count"`
+	SlowCount     int       `json:"slow_count"`
+	FailureCount  int       `json:"failure_count"`
+	LastResult    string    `json:"last_result"`
+	LastDuration  string    `json:"last_duration"`
+	LastCheckedAt time.Time `json:"last_checked_at"`
+	LastSuccessAt time.Time `json:"last_success_at"`
+	Uptime        string    `json:"uptime"`
+
+	Percentiles   map[string]float64 `json:"percentiles_ms"`
+	TopSlowest    []queryResult      `json:"top_slowest_5m"`
+	LastDurations []string           `json:"last_durations"`
+}
+
+func (s *apiServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	snap := s.stats.Snapshot()
+
+	percentiles := map[string]float64{}
+	for p, d := range s.stats.Percentiles(percentileList, true) {
+		percentiles[strconv.FormatFloat(p, 'f', -1, 64)] = float64(d.Milliseconds())
+	}
+
+	lastDurations := make([]string, len(snap.LastDurations))
+	for i, d := range snap.LastDurations {
+		lastDurations[i] = d.String()
+	}
+
+	resp := statsResponse{
+		SuccessCount:  snap.SuccessCount,
+		SlowCount:     snap.SlowCount,
+		FailureCount:  snap.FailureCount,
+		LastResult:    snap.LastResult,
+		LastDuration:  snap.LastDuration.String(),
+		LastCheckedAt: snap.LastCheckedAt,
+		LastSuccessAt: snap.LastSuccessAt,
+		Uptime:        snap.Uptime.String(),
+		Percentiles:   percentiles,
+		TopSlowest:    getTopSlowest(snap.ResultHistory),
+		LastDurations: lastDurations,
+	}
+
+	writeJSON(w, resp)
+}
+
+func (s *apiServer) handleQuerylog(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	offset := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			offset = n
+		}
+	}
+
+	entries := s.qlog.Recent(offset, limit)
+	writeJSON(w, map[string]any{
+		"offset":  offset,
+		"limit":   limit,
+		"entries": entries,
+	})
+}
+
+func (s *apiServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	snap := s.stats.Snapshot()
+	if snap.LastSuccessAt.IsZero() || time.Since(snap.LastSuccessAt) > healthzThreshold {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "unhealthy: last success %v ago\n", time.Since(snap.LastSuccessAt))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "ok")
+}
+
+func (s *apiServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	snap := s.stats.Snapshot()
+	hist := s.stats.Histogram()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP dns_probe_success_total Successful probes.")
+	fmt.Fprintln(w, "# TYPE dns_probe_success_total counter")
+	fmt.Fprintf(w, "dns_probe_success_total %d\n", snap.SuccessCount)
+
+	fmt.Fprintln(w, "# HELP dns_probe_slow_total Probes slower than the slow-response threshold.")
+	fmt.Fprintln(w, "# TYPE dns_probe_slow_total counter")
+	fmt.Fprintf(w, "dns_probe_slow_total %d\n", snap.SlowCount)
+
+	fmt.Fprintln(w, "# HELP dns_probe_fail_total Failed probes.")
+	fmt.Fprintln(w, "# TYPE dns_probe_fail_total counter")
+	fmt.Fprintf(w, "dns_probe_fail_total %d\n", snap.FailureCount)
+
+	fmt.Fprintln(w, "# HELP dns_probe_duration_seconds Probe duration histogram.")
+	fmt.Fprintln(w, "# TYPE dns_probe_duration_seconds histogram")
+	var cumulative uint64
+	for i, bound := range hist.Bounds {
+		cumulative += hist.Counts[i]
+		fmt.Fprintf(w, "dns_probe_duration_seconds_bucket{le=\"%s\"} %d\n", strconv.FormatFloat(bound, 'f', -1, 64), cumulative)
+	}
+	cumulative += hist.Counts[len(hist.Bounds)]
+	fmt.Fprintf(w, "dns_probe_duration_seconds_bucket{le=\"+Inf\"} %d\n", cumulative)
+	fmt.Fprintf(w, "dns_probe_duration_seconds_sum %v\n", hist.Sum)
+	fmt.Fprintf(w, "dns_probe_duration_seconds_count %d\n", hist.Total)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAPIServer(t *testing.T) (*apiServer, *Stats, *querylog) {
+	t.Helper()
+	stats := newStats()
+	qlog, err := newQuerylog(t.TempDir() + "/dns_results.log")
+	if err != nil {
+		t.Fatalf("newQuerylog: %v", err)
+	}
+	t.Cleanup(func() { qlog.Close() })
+	return newAPIServer(stats, qlog), stats, qlog
+}
+
+func TestHandleStatsShape(t *testing.T) {
+	api, stats, qlog := newTestAPIServer(t)
+	entry := stats.Record(time.Now(), []probeResult{{IPs: []string{"1.2.3.4"}, Duration: 10 * time.Millisecond}})
+	qlog.Append(entry)
+
+	rr := httptest.NewRecorder()
+	api.mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var resp statsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode /stats body: %v (body: %s)", err, rr.Body.String())
+	}
+	if resp.SuccessCount != 1 {
+		t.Errorf("SuccessCount = %d, want 1", resp.SuccessCount)
+	}
+	if resp.LastResult == "" {
+		t.Error("LastResult should not be empty")
+	}
+}
+
+func TestHandleQuerylogPagination(t *testing.T) {
+	api, stats, qlog := newTestAPIServer(t)
+	for i := 0; i < 5; i++ {
+		entry := stats.Record(time.Now(), []probeResult{{IPs: []string{"1.2.3.4"}, Duration: time.Millisecond}})
+		qlog.Append(entry)
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/querylog?limit=2&offset=1", nil)
+	api.mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rr.Code)
+	}
+
+	var body struct {
+		Offset  int             `json:"offset"`
+		Limit   int             `json:"limit"`
+		Entries []querylogEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode /querylog body: %v", err)
+	}
+	if body.Offset != 1 || body.Limit != 2 {
+		t.Errorf("offset/limit = %d/%d, want 1/2", body.Offset, body.Limit)
+	}
+	if len(body.Entries) != 2 {
+		t.Errorf("len(entries) = %d, want 2", len(body.Entries))
+	}
+}
+
+func TestHandleHealthzUnhealthyBeforeFirstSuccess(t *testing.T) {
+	api, _, _ := newTestAPIServer(t)
+
+	rr := httptest.NewRecorder()
+	api.mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503 before any successful probe", rr.Code)
+	}
+}
+
+func TestHandleHealthzHealthyAfterRecentSuccess(t *testing.T) {
+	api, stats, _ := newTestAPIServer(t)
+	stats.Record(time.Now(), []probeResult{{IPs: []string{"1.2.3.4"}, Duration: time.Millisecond}})
+
+	rr := httptest.NewRecorder()
+	api.mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 right after a success", rr.Code)
+	}
+}
+
+func TestHandleMetricsFormat(t *testing.T) {
+	api, stats, _ := newTestAPIServer(t)
+	stats.Record(time.Now(), []probeResult{{IPs: []string{"1.2.3.4"}, Duration: time.Millisecond}})
+
+	rr := httptest.NewRecorder()
+	api.mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rr.Code)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"dns_probe_success_total 1",
+		"# TYPE dns_probe_duration_seconds histogram",
+		`dns_probe_duration_seconds_bucket{le="+Inf"}`,
+		"dns_probe_duration_seconds_count 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/metrics body missing %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestAPIAddrFromEnv(t *testing.T) {
+	t.Setenv("DNS_API_ADDR", "")
+	if got := apiAddrFromEnv(); got != defaultAPIAddr {
+		t.Errorf("apiAddrFromEnv() with no env = %q, want default %q", got, defaultAPIAddr)
+	}
+
+	t.Setenv("DNS_API_ADDR", "127.0.0.1:"+strconv.Itoa(9999))
+	if got := apiAddrFromEnv(); got != "127.0.0.1:9999" {
+		t.Errorf("apiAddrFromEnv() = %q, want 127.0.0.1:9999", got)
+	}
+}
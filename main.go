@@ -2,18 +2,17 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	slowResponseDuration = 5 * time.Second
 	interval             = 50 * time.Millisecond
-	logFilePath          = "dns_results.log"
 	maxHistoryWindow     = 5 * time.Minute
 	topN                 = 5
 	maxRecords           = 1000000
@@ -24,17 +23,66 @@ type queryResult struct {
 	Duration  time.Duration
 }
 
-var (
-	successCount    int
-	slowCount       int
-	failureCount    int
-	lastResult      string
-	lastDuration    time.Duration
-	lastResolvedIPs []string
-	resultHistory   []queryResult
-	lastDurations   []time.Duration
-	startTime       = time.Now()
-)
+var stats = newStats()
+
+// runtimeConfig holds the probe parameters the TUI lets a user change while
+// the monitor is running: whether probing is paused and which
+// hostname/resolvers to probe. Guarded by mu so the probe loop (reader) and
+// the TUI's key handlers (writer) never race.
+type runtimeConfig struct {
+	mu        sync.Mutex
+	paused    bool
+	hostname  string
+	resolvers []Resolver
+}
+
+func newRuntimeConfig(hostname string, resolvers []Resolver) *runtimeConfig {
+	return &runtimeConfig{hostname: hostname, resolvers: resolvers}
+}
+
+func (c *runtimeConfig) TogglePaused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = !c.paused
+	return c.paused
+}
+
+func (c *runtimeConfig) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+func (c *runtimeConfig) Hostname() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hostname
+}
+
+func (c *runtimeConfig) SetHostname(hostname string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hostname = hostname
+}
+
+func (c *runtimeConfig) Resolvers() []Resolver {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.resolvers
+}
+
+// SetUpstream replaces the configured resolvers with a single upstream built
+// from spec (see newUpstreamResolver for the accepted forms).
+func (c *runtimeConfig) SetUpstream(spec string) error {
+	u, err := newUpstreamResolver(spec)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resolvers = []Resolver{u}
+	return nil
+}
 
 func clearTerminal() {
 	cmd := exec.Command("clear")
@@ -49,49 +97,70 @@ func main() {
 		os.Exit(1)
 	}
 
-	logFile, err := os.OpenFile(logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	qlog, err := newQuerylog(querylogPath)
 	if err != nil {
-		log.Fatalf("Failed to open log file: %v", err)
+		fmt.Printf("❌ Failed to open query log: %v\n", err)
+		os.Exit(1)
 	}
-	defer logFile.Close()
-	logger := log.New(logFile, "", log.LstdFlags)
+	defer qlog.Close()
+	go qlog.runHourlyRotation()
 
-	fmt.Printf("🔍 Starting DNS probe for hostname: %s\n", hostname)
+	api := newAPIServer(stats, qlog)
+	go api.serve(apiAddrFromEnv())
 
-	for {
-		now := time.Now()
-		ips, duration, err := resolveHostnameWithDig(hostname)
-
-		if err != nil {
-			failureCount++
-			lastResult = fmt.Sprintf("❌ FAIL (%v)", err)
-			lastDuration = duration
-			lastResolvedIPs = nil
-			logger.Printf("[%s] FAIL - Error: %v - Time: %v\n", now.Format("2006-01-02 15:04:05.000"), err, duration)
-		} else if duration > slowResponseDuration {
-			slowCount++
-			lastResult = "🐢 SLOW"
-			lastDuration = duration
-			lastResolvedIPs = ips
-			logger.Printf("[%s] SLOW - IPs: %v - Time: %v\n", now.Format("2006-01-02 15:04:05.000"), ips, duration)
-		} else {
-			successCount++
-			lastResult = "✅ SUCCESS"
-			lastDuration = duration
-			lastResolvedIPs = ips
-			logger.Printf("[%s] OK - IPs: %v - Time: %v\n", now.Format("2006-01-02 15:04:05.000"), ips, duration)
+	resolvers := resolversFromEnv()
+	qtype := recordTypeFromEnv()
+	cfg := newRuntimeConfig(hostname, resolvers)
+
+	scheduler := schedulerFromEnv()
+	go scheduler.Run()
+	defer scheduler.Stop()
+
+	shedder := loadShedderFromEnv()
+
+	plain := plainModeRequested()
+
+	go runProbeLoop(scheduler, shedder, cfg, qtype, qlog, plain)
+
+	if plain {
+		fmt.Printf("🔍 Starting DNS probe for hostname: %s\n", hostname)
+		select {}
+	}
+
+	if err := runTUI(stats, qlog, scheduler, cfg); err != nil {
+		fmt.Printf("❌ TUI failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runProbeLoop drives the probe schedule, recording each result into stats
+// and the query log. It reads hostname/resolvers/paused from cfg on every
+// tick so the TUI can change them while the loop is running. In --plain
+// mode it also renders printStats after every probe, matching the original
+// terminal output for non-TTY environments (CI, `docker logs`).
+func runProbeLoop(scheduler *Scheduler, shedder *loadShedder, cfg *runtimeConfig, qtype uint16, qlog *querylog, plain bool) {
+	for range scheduler.C {
+		if cfg.Paused() || shedder.ShouldSkip() {
+			continue
 		}
 
-		resultHistory = append(resultHistory, queryResult{Timestamp: now, Duration: duration})
-		cleanupOldResults()
-		updateLastDurations(duration)
+		hostname := cfg.Hostname()
+		now := time.Now()
+		results := probeAll(cfg.Resolvers(), hostname, qtype)
+		scheduler.Observe(results[0].Err, results[0].Duration)
+
+		entry := stats.Record(now, results)
+		qlog.Append(entry)
 
-		printStats(hostname)
-		time.Sleep(interval)
+		if plain {
+			printStats(hostname, qlog)
+		}
 	}
 }
 
-// resolveHostnameWithDig uses the `dig` command to resolve a hostname.
+// resolveHostnameWithDig shells out to the `dig` command to resolve a
+// hostname. It backs the digResolver compatibility fallback for
+// environments where the native resolver (see resolver.go) isn't wanted.
 func resolveHostnameWithDig(hostname string) ([]string, time.Duration, error) {
 	start := time.Now()
 
@@ -114,68 +183,74 @@ func resolveHostnameWithDig(hostname string) ([]string, time.Duration, error) {
 	return ips, duration, nil
 }
 
-func cleanupOldResults() {
-	if len(resultHistory) > maxRecords {
-		resultHistory = resultHistory[len(resultHistory)-maxRecords:]
-	}
-
-	cutoff := time.Now().Add(-maxHistoryWindow)
-	filtered := resultHistory[:0]
-	for _, r := range resultHistory {
-		if r.Timestamp.After(cutoff) {
-			filtered = append(filtered, r)
-		}
-	}
-	resultHistory = filtered
-}
-
-func updateLastDurations(duration time.Duration) {
-	lastDurations = append(lastDurations, duration)
-	if len(lastDurations) > 5 {
-		lastDurations = lastDurations[1:]
-	}
-}
-
-func printStats(hostname string) {
+func printStats(hostname string, qlog *querylog) {
 	clearTerminal()
 
-	uptime := time.Since(startTime).Truncate(time.Second)
+	snap := stats.Snapshot()
+
 	fmt.Printf("📡 DNS Monitor\n")
 	fmt.Printf("🌐 Resolving Hostname: %s\n", hostname)
-	fmt.Printf("⏱️  Uptime: %v\n\n", uptime)
+	fmt.Printf("⏱️  Uptime: %v\n\n", snap.Uptime)
 
-	fmt.Printf("✅ Successes     : %d\n", successCount)
-	fmt.Printf("🐢 Slow Responses: %d\n", slowCount)
-	fmt.Printf("❌ Failures      : %d\n", failureCount)
+	fmt.Printf("✅ Successes     : %d\n", snap.SuccessCount)
+	fmt.Printf("🐢 Slow Responses: %d\n", snap.SlowCount)
+	fmt.Printf("❌ Failures      : %d\n", snap.FailureCount)
 
 	fmt.Println("\n📊 Last Attempt:")
-	fmt.Printf("   Result       : %s\n", lastResult)
-	fmt.Printf("   Duration     : %v\n", lastDuration)
-	if len(lastResolvedIPs) > 0 {
-		fmt.Printf("   Resolved IPs : %v\n", lastResolvedIPs)
+	fmt.Printf("   Result       : %s\n", snap.LastResult)
+	fmt.Printf("   Duration     : %v\n", snap.LastDuration)
+	if len(snap.LastResolvedIPs) > 0 {
+		fmt.Printf("   Resolved IPs : %v\n", snap.LastResolvedIPs)
 	}
 
 	fmt.Println("\n🧮 Last 5 Durations:")
-	for i, d := range lastDurations {
+	for i, d := range snap.LastDurations {
 		fmt.Printf("   %d. %v\n", i+1, d)
 	}
 
-	top := getTopSlowest()
+	if len(snap.LastPerResolver) > 1 {
+		fmt.Println("\n🌍 Per-Resolver:")
+		for _, r := range snap.LastPerResolver {
+			if r.Err != nil {
+				fmt.Printf("   %-20s ❌ %v\n", r.Resolver, r.Err)
+			} else {
+				fmt.Printf("   %-20s %v - %v\n", r.Resolver, r.Duration, r.IPs)
+			}
+		}
+	}
+
+	top := getTopSlowest(snap.ResultHistory)
 	fmt.Println("\n⏱️  Top 5 Slowest in Last 5 Minutes:")
 	for i, r := range top {
 		fmt.Printf("   %d. %s - %v\n", i+1, r.Timestamp.Format("15:04:05.000"), r.Duration)
 	}
 
-	fmt.Println("\n📈 Duration Percentiles (Last 10,000 Records):")
-	printPercentiles(resultHistory)
+	topIPs, topFailures, _ := qlog.Top24h(topN)
+	fmt.Println("\n📚 Top IPs over Last 24h:")
+	printCounterEntries(topIPs)
+	fmt.Println("\n🚫 Top Failure Reasons over Last 24h:")
+	printCounterEntries(topFailures)
+
+	fmt.Println("\n📈 Duration Percentiles (5-Minute Window):")
+	printPercentiles(stats.Percentiles(percentileList, true))
 
 	fmt.Println("\n(Press Ctrl+C to stop)")
 }
 
-func getTopSlowest() []queryResult {
+func printCounterEntries(entries []counterEntry) {
+	if len(entries) == 0 {
+		fmt.Println("   (no data yet)")
+		return
+	}
+	for i, e := range entries {
+		fmt.Printf("   %d. %s (%d)\n", i+1, e.Key, e.Count)
+	}
+}
+
+func getTopSlowest(history []queryResult) []queryResult {
 	cutoff := time.Now().Add(-maxHistoryWindow)
 	slowResults := []queryResult{}
-	for _, r := range resultHistory {
+	for _, r := range history {
 		if r.Timestamp.After(cutoff) {
 			slowResults = append(slowResults, r)
 		}
@@ -190,44 +265,23 @@ func getTopSlowest() []queryResult {
 	return slowResults
 }
 
-func printPercentiles(history []queryResult) {
-	if len(history) == 0 {
+// percentileList is the set of quantiles printPercentiles renders, in
+// display order; 99.9 is shown as "P99.9" rather than "P100".
+var percentileList = []float64{50, 75, 90, 95, 99, 99.9}
+
+func printPercentiles(percentiles map[float64]time.Duration) {
+	max := percentiles[percentileList[len(percentileList)-1]]
+	if max == 0 {
 		fmt.Println("   (no data yet)")
 		return
 	}
 
-	durations := make([]float64, len(history))
-	for i, r := range history {
-		durations[i] = float64(r.Duration.Milliseconds())
-	}
-	sort.Float64s(durations)
-
-	percentiles := []int{50, 75, 90, 95, 99, 999}
-	for _, p := range percentiles {
-		if p == 999 {
-			val := percentile(durations, 99.9)
-			bar := buildBar(val, durations[len(durations)-1])
-			fmt.Printf("P99.9 │ %s %dms\n", bar, int(val))
-		} else {
-			val := percentile(durations, float64(p))
-			bar := buildBar(val, durations[len(durations)-1])
-			fmt.Printf("P%02d   │ %s %dms\n", p, bar, int(val))
-		}
-	}
-}
-
-func percentile(sorted []float64, percent float64) float64 {
-	if len(sorted) == 0 {
-		return 0
-	}
-	k := percent / 100 * float64(len(sorted)-1)
-	f := int(k)
-	c := f + 1
-	if c >= len(sorted) {
-		return sorted[f]
+	for _, p := range percentileList {
+		val := percentiles[p]
+		bar := buildBar(float64(val.Milliseconds()), float64(max.Milliseconds()))
+		label := fmt.Sprintf("P%02g", p)
+		fmt.Printf("%-5s │ %s %dms\n", label, bar, val.Milliseconds())
 	}
-	d := k - float64(f)
-	return sorted[f]*(1-d) + sorted[c]*d
 }
 
 func buildBar(value float64, max float64) string {
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	querylogPath         = "dns_results.log"
+	querylogMaxBytes     = 10 * 1024 * 1024 // rotate once the active file crosses 10MB
+	querylogMaxBackups   = 5
+	querylogReloadWindow = 24 * time.Hour
+)
+
+// querylogEntry is one probe result as persisted to the rotating JSONL log.
+type querylogEntry struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Result    string        `json:"result"` // "ok", "slow", or "fail"
+	IPs       []string      `json:"ips,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration_ns"`
+}
+
+const querylogRecentCap = 2000
+
+// querylog appends probe results to a size-rotated JSONL file and keeps an
+// in-memory ring of per-hour-of-day buckets for the last 24 hours, mirroring
+// the day/hour top structure used by AdGuard's querylog subsystem.
+type querylog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+
+	hours  [24]*hourTop
+	recent []querylogEntry // newest last, bounded to querylogRecentCap
+}
+
+// hourTop holds LRU-bounded counters for one hour-of-day bucket: resolved
+// IPs, failure reasons, and slow-response causes.
+type hourTop struct {
+	hour        int
+	resolvedIPs *lruCounter
+	failReasons *lruCounter
+	slowCauses  *lruCounter
+}
+
+const hourTopCapacity = 64
+
+func newHourTop(hour int) *hourTop {
+	return &hourTop{
+		hour:        hour,
+		resolvedIPs: newLRUCounter(hourTopCapacity),
+		failReasons: newLRUCounter(hourTopCapacity),
+		slowCauses:  newLRUCounter(hourTopCapacity),
+	}
+}
+
+func (h *hourTop) reset() {
+	h.resolvedIPs = newLRUCounter(hourTopCapacity)
+	h.failReasons = newLRUCounter(hourTopCapacity)
+	h.slowCauses = newLRUCounter(hourTopCapacity)
+}
+
+// newQuerylog opens (or creates) the rotating log file and reloads the last
+// querylogReloadWindow of history into the hourly buckets so a restart
+// doesn't erase recent stats.
+func newQuerylog(path string) (*querylog, error) {
+	q := &querylog{path: path}
+	for i := range q.hours {
+		q.hours[i] = newHourTop(i)
+	}
+
+	if err := q.reload(); err != nil {
+		fmt.Printf("⚠️  querylog: reload failed: %v\n", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("querylog: open %s: %w", path, err)
+	}
+	q.file = f
+	return q, nil
+}
+
+// reload reads every backup generation (path.querylogMaxBackups down to
+// path.1, oldest first) plus the active file to repopulate the hourly
+// buckets for the trailing querylogReloadWindow, not just the newest
+// rotation.
+func (q *querylog) reload() error {
+	cutoff := time.Now().Add(-querylogReloadWindow)
+
+	var paths []string
+	for i := querylogMaxBackups; i >= 1; i-- {
+		paths = append(paths, fmt.Sprintf("%s.%d", q.path, i))
+	}
+	paths = append(paths, q.path)
+
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var entry querylogEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue
+			}
+			if entry.Timestamp.Before(cutoff) {
+				continue
+			}
+			q.record(entry)
+		}
+		f.Close()
+	}
+	return nil
+}
+
+// Append persists entry to the log file and folds it into the current
+// hour's bucket, rotating the file first if it has grown past the size
+// threshold.
+func (q *querylog) Append(entry querylogEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.record(entry)
+
+	if err := q.rotateIfNeeded(); err != nil {
+		fmt.Printf("⚠️  querylog: rotate failed: %v\n", err)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if _, err := q.file.Write(append(line, '\n')); err != nil {
+		fmt.Printf("⚠️  querylog: write failed: %v\n", err)
+	}
+}
+
+func (q *querylog) record(entry querylogEntry) {
+	q.recent = append(q.recent, entry)
+	if len(q.recent) > querylogRecentCap {
+		q.recent = q.recent[len(q.recent)-querylogRecentCap:]
+	}
+
+	bucket := q.hours[entry.Timestamp.Hour()]
+	switch entry.Result {
+	case "ok", "slow":
+		for _, ip := range entry.IPs {
+			bucket.resolvedIPs.Incr(ip)
+		}
+		if entry.Result == "slow" {
+			bucket.slowCauses.Incr(entry.Duration.Round(time.Second).String())
+		}
+	case "fail":
+		bucket.failReasons.Incr(entry.Error)
+	}
+}
+
+// rotateIfNeeded renames the active file to path.1 (discarding any older
+// backup beyond querylogMaxBackups) once it crosses querylogMaxBytes.
+func (q *querylog) rotateIfNeeded() error {
+	info, err := q.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < querylogMaxBytes {
+		return nil
+	}
+
+	if err := q.file.Close(); err != nil {
+		return err
+	}
+
+	for i := querylogMaxBackups - 1; i >= 1; i-- {
+		src := fmt.Sprintf("%s.%d", q.path, i)
+		dst := fmt.Sprintf("%s.%d", q.path, i+1)
+		if _, err := os.Stat(src); err == nil {
+			_ = os.Rename(src, dst)
+		}
+	}
+	if err := os.Rename(q.path, q.path+".1"); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(q.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	q.file = f
+	return nil
+}
+
+// runHourlyRotation clears out the bucket for the hour-of-day that is just
+// starting (it still holds entries from 24 hours earlier), sleeping until
+// each wall-clock hour boundary rather than ticking every time.Hour from
+// process start — record() buckets entries by entry.Timestamp.Hour() (the
+// actual wall-clock hour), so resets anchored anywhere else clear (and stop
+// reaching) the wrong bucket.
+func (q *querylog) runHourlyRotation() {
+	for {
+		next := nextHourBoundary(time.Now())
+		time.Sleep(time.Until(next))
+
+		q.mu.Lock()
+		q.hours[next.Hour()].reset()
+		q.mu.Unlock()
+	}
+}
+
+// nextHourBoundary returns the next wall-clock hour boundary strictly after t,
+// in t's location.
+func nextHourBoundary(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+}
+
+// Recent returns up to limit entries, newest first, skipping the first
+// offset of them — used to paginate the /querylog HTTP endpoint.
+func (q *querylog) Recent(offset, limit int) []querylogEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := len(q.recent)
+	start := n - offset
+	if start > n {
+		start = n
+	}
+	if start < 0 {
+		return nil
+	}
+	end := start - limit
+	if end < 0 {
+		end = 0
+	}
+
+	page := make([]querylogEntry, 0, start-end)
+	for i := start - 1; i >= end; i-- {
+		page = append(page, q.recent[i])
+	}
+	return page
+}
+
+// Top24h returns the combined top-N resolved IPs, failure reasons, and slow
+// causes across all 24 hourly buckets.
+func (q *querylog) Top24h(n int) (ips, failures, slow []counterEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	merged := newLRUCounter(0)
+	mergedFail := newLRUCounter(0)
+	mergedSlow := newLRUCounter(0)
+	for _, h := range q.hours {
+		merged.Merge(h.resolvedIPs)
+		mergedFail.Merge(h.failReasons)
+		mergedSlow.Merge(h.slowCauses)
+	}
+	return merged.TopN(n), mergedFail.TopN(n), mergedSlow.TopN(n)
+}
+
+func (q *querylog) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.file.Close()
+}
+
+// counterEntry is one (key, count) pair from a lruCounter's top-N.
+type counterEntry struct {
+	Key   string
+	Count int
+}
+
+// lruCounter is a capacity-bounded counter: once full, incrementing a new
+// key evicts the least-recently-used existing key. A capacity of 0 means
+// unbounded, used for the scratch counters Top24h merges into.
+type lruCounter struct {
+	capacity int
+	counts   map[string]int
+	order    []string // most-recently-used at the end
+}
+
+func newLRUCounter(capacity int) *lruCounter {
+	return &lruCounter{capacity: capacity, counts: make(map[string]int)}
+}
+
+func (c *lruCounter) Incr(key string) {
+	if key == "" {
+		return
+	}
+	if _, ok := c.counts[key]; ok {
+		c.counts[key]++
+		c.touch(key)
+		return
+	}
+
+	if c.capacity > 0 && len(c.counts) >= c.capacity {
+		c.evictOldest()
+	}
+	c.counts[key] = 1
+	c.order = append(c.order, key)
+}
+
+func (c *lruCounter) touch(key string) {
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+	c.order = append(c.order, key)
+}
+
+func (c *lruCounter) evictOldest() {
+	if len(c.order) == 0 {
+		return
+	}
+	oldest := c.order[0]
+	c.order = c.order[1:]
+	delete(c.counts, oldest)
+}
+
+func (c *lruCounter) Merge(other *lruCounter) {
+	for k, v := range other.counts {
+		c.counts[k] += v
+	}
+}
+
+func (c *lruCounter) TopN(n int) []counterEntry {
+	entries := make([]counterEntry, 0, len(c.counts))
+	for k, v := range c.counts {
+		entries = append(entries, counterEntry{Key: k, Count: v})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Count > entries[j].Count
+	})
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeJSONLines writes one JSON line per entry to path, creating or
+// truncating the file; its parent directory must already exist.
+func writeJSONLines(t *testing.T, path string, entries []querylogEntry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create %s: %v", path, err)
+	}
+	defer f.Close()
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			t.Fatalf("marshal entry: %v", err)
+		}
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+}
+
+func TestQuerylogRotateIfNeededWalksBackupChain(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dns_results.log")
+
+	q, err := newQuerylog(path)
+	if err != nil {
+		t.Fatalf("newQuerylog: %v", err)
+	}
+	defer q.Close()
+
+	// Seed two existing backup generations so rotation has something to
+	// shift down the chain.
+	if err := os.WriteFile(path+".1", []byte("gen1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".2", []byte("gen2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Grow the active file past querylogMaxBytes without writing that much
+	// data through Append.
+	if _, err := q.file.Write(make([]byte, querylogMaxBytes)); err != nil {
+		t.Fatalf("grow active file: %v", err)
+	}
+
+	if err := q.rotateIfNeeded(); err != nil {
+		t.Fatalf("rotateIfNeeded: %v", err)
+	}
+
+	gen1, err := os.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatalf("read %s.1: %v", path, err)
+	}
+	if len(gen1) != querylogMaxBytes {
+		t.Errorf("path.1 should now hold the rotated active file (%d bytes), got %d", querylogMaxBytes, len(gen1))
+	}
+
+	gen2, err := os.ReadFile(path + ".2")
+	if err != nil {
+		t.Fatalf("read %s.2: %v", path, err)
+	}
+	if string(gen2) != "gen1\n" {
+		t.Errorf("path.2 should hold the old path.1 contents, got %q", gen2)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("active file should have been recreated: %v", err)
+	}
+}
+
+func TestQuerylogReloadWalksAllBackupGenerations(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dns_results.log")
+
+	now := time.Now()
+	withinWindow := now.Add(-23 * time.Hour)
+	outsideWindow := now.Add(-25 * time.Hour)
+
+	// Spread entries within the reload window across every backup
+	// generation, oldest first, plus one entry too old to count.
+	writeJSONLines(t, fmt.Sprintf("%s.%d", path, querylogMaxBackups), []querylogEntry{
+		{Timestamp: outsideWindow, Result: "ok", IPs: []string{"10.0.0.254"}},
+	})
+	for i := querylogMaxBackups - 1; i >= 1; i-- {
+		writeJSONLines(t, fmt.Sprintf("%s.%d", path, i), []querylogEntry{
+			{Timestamp: withinWindow, Result: "ok", IPs: []string{fmt.Sprintf("10.0.0.%d", i)}},
+		})
+	}
+	writeJSONLines(t, path, []querylogEntry{
+		{Timestamp: now, Result: "ok", IPs: []string{"10.0.0.100"}},
+	})
+
+	q, err := newQuerylog(path)
+	if err != nil {
+		t.Fatalf("newQuerylog: %v", err)
+	}
+	defer q.Close()
+
+	ips, _, _ := q.Top24h(querylogMaxBackups + 1)
+
+	wantCount := querylogMaxBackups // one per generation 1..N-1, plus the active file
+	if len(ips) != wantCount {
+		t.Fatalf("Top24h returned %d IPs, want %d (entries outside the reload window should be excluded): %+v", len(ips), wantCount, ips)
+	}
+	for _, e := range ips {
+		if e.Key == "10.0.0.254" {
+			t.Errorf("entry older than querylogReloadWindow should not have been reloaded, got %+v", e)
+		}
+	}
+}
+
+func TestNextHourBoundary(t *testing.T) {
+	cases := []struct {
+		name string
+		in   time.Time
+		want time.Time
+	}{
+		{
+			name: "mid-hour",
+			in:   time.Date(2026, 7, 30, 14, 32, 10, 0, time.UTC),
+			want: time.Date(2026, 7, 30, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "exact boundary still advances to the next hour",
+			in:   time.Date(2026, 7, 30, 14, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 7, 30, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "crosses midnight into the next day",
+			in:   time.Date(2026, 7, 30, 23, 45, 0, 0, time.UTC),
+			want: time.Date(2026, 7, 31, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := nextHourBoundary(c.in); !got.Equal(c.want) {
+				t.Errorf("nextHourBoundary(%v) = %v, want %v", c.in, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Resolver performs a single DNS lookup for hostname and reports the
+// wire-level (or process) latency it took.
+type Resolver interface {
+	// Name identifies the resolver for per-resolver stats attribution.
+	Name() string
+	Resolve(hostname string, qtype uint16) ([]string, time.Duration, error)
+}
+
+// recordTypes maps the env-var-friendly record names to miekg/dns's
+// numeric query types.
+var recordTypes = map[string]uint16{
+	"A":     dns.TypeA,
+	"AAAA":  dns.TypeAAAA,
+	"CNAME": dns.TypeCNAME,
+	"MX":    dns.TypeMX,
+	"TXT":   dns.TypeTXT,
+	"NS":    dns.TypeNS,
+}
+
+// recordTypeFromEnv resolves DNS_RECORD_TYPE to a query type, defaulting to A.
+func recordTypeFromEnv() uint16 {
+	name := strings.ToUpper(strings.TrimSpace(os.Getenv("DNS_RECORD_TYPE")))
+	if name == "" {
+		return dns.TypeA
+	}
+	if qtype, ok := recordTypes[name]; ok {
+		return qtype
+	}
+	fmt.Printf("⚠️  Unknown DNS_RECORD_TYPE %q, defaulting to A\n", name)
+	return dns.TypeA
+}
+
+// upstreamResolver queries a single upstream server over udp, tcp, tls
+// (DoT) or https (DoH).
+type upstreamResolver struct {
+	name    string
+	network string // "udp", "tcp", "tcp-tls", or "https"
+	addr    string
+	client  *dns.Client
+}
+
+// newUpstreamResolver builds a resolver for spec strings like:
+//
+//	8.8.8.8:53            (udp, default)
+//	udp://1.1.1.1:53
+//	tcp://1.1.1.1:53
+//	tls://1.1.1.1:853     (DNS-over-TLS)
+//	https://dns.google/dns-query (DNS-over-HTTPS)
+func newUpstreamResolver(spec string) (*upstreamResolver, error) {
+	network := "udp"
+	addr := spec
+
+	if idx := strings.Index(spec, "://"); idx != -1 {
+		scheme := spec[:idx]
+		addr = spec[idx+3:]
+		switch scheme {
+		case "udp":
+			network = "udp"
+		case "tcp":
+			network = "tcp"
+		case "tls":
+			network = "tcp-tls"
+		case "https":
+			return &upstreamResolver{name: spec, network: "https", addr: spec}, nil
+		default:
+			return nil, fmt.Errorf("unsupported resolver scheme %q", scheme)
+		}
+	}
+
+	if network != "https" && !strings.Contains(addr, ":") {
+		addr += ":53"
+	}
+
+	client := &dns.Client{Net: network, Timeout: 5 * time.Second}
+	if network == "tcp-tls" {
+		client.TLSConfig = &tls.Config{ServerName: hostFromAddr(addr)}
+	}
+
+	return &upstreamResolver{name: spec, network: network, addr: addr, client: client}, nil
+}
+
+func hostFromAddr(addr string) string {
+	host, _, err := splitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+func splitHostPort(addr string) (string, string, error) {
+	idx := strings.LastIndex(addr, ":")
+	if idx == -1 {
+		return addr, "", fmt.Errorf("no port in address %q", addr)
+	}
+	return addr[:idx], addr[idx+1:], nil
+}
+
+func (r *upstreamResolver) Name() string {
+	return r.name
+}
+
+func (r *upstreamResolver) Resolve(hostname string, qtype uint16) ([]string, time.Duration, error) {
+	if r.network == "https" {
+		return resolveDoH(r.addr, hostname, qtype)
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(hostname), qtype)
+	msg.RecursionDesired = true
+
+	resp, rtt, err := r.client.Exchange(msg, r.addr)
+	if err != nil {
+		return nil, rtt, fmt.Errorf("%s: exchange failed: %w", r.name, err)
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return nil, rtt, fmt.Errorf("%s: bad rcode %s", r.name, dns.RcodeToString[resp.Rcode])
+	}
+
+	answers := answersToStrings(resp.Answer, qtype)
+	if len(answers) == 0 {
+		return nil, rtt, fmt.Errorf("%s: no records found", r.name)
+	}
+	return answers, rtt, nil
+}
+
+// resolveDoH performs a DNS-over-HTTPS lookup using RFC 8484's wire-format
+// POST request (Content-Type: application/dns-message).
+func resolveDoH(endpoint, hostname string, qtype uint16) ([]string, time.Duration, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(hostname), qtype)
+	msg.RecursionDesired = true
+	msg.Id = 0
+
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, 0, fmt.Errorf("doh: pack query: %w", err)
+	}
+
+	start := time.Now()
+	respBytes, err := dohExchange(endpoint, packed)
+	rtt := time.Since(start)
+	if err != nil {
+		return nil, rtt, fmt.Errorf("doh %s: %w", endpoint, err)
+	}
+
+	resp := new(dns.Msg)
+	if err := resp.Unpack(respBytes); err != nil {
+		return nil, rtt, fmt.Errorf("doh %s: unpack response: %w", endpoint, err)
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return nil, rtt, fmt.Errorf("doh %s: bad rcode %s", endpoint, dns.RcodeToString[resp.Rcode])
+	}
+
+	answers := answersToStrings(resp.Answer, qtype)
+	if len(answers) == 0 {
+		return nil, rtt, fmt.Errorf("doh %s: no records found", endpoint)
+	}
+	return answers, rtt, nil
+}
+
+func answersToStrings(answers []dns.RR, qtype uint16) []string {
+	var out []string
+	for _, rr := range answers {
+		switch qtype {
+		case dns.TypeA:
+			if a, ok := rr.(*dns.A); ok {
+				out = append(out, a.A.String())
+			}
+		case dns.TypeAAAA:
+			if aaaa, ok := rr.(*dns.AAAA); ok {
+				out = append(out, aaaa.AAAA.String())
+			}
+		case dns.TypeCNAME:
+			if c, ok := rr.(*dns.CNAME); ok {
+				out = append(out, c.Target)
+			}
+		case dns.TypeMX:
+			if mx, ok := rr.(*dns.MX); ok {
+				out = append(out, fmt.Sprintf("%d %s", mx.Preference, mx.Mx))
+			}
+		case dns.TypeTXT:
+			if txt, ok := rr.(*dns.TXT); ok {
+				out = append(out, strings.Join(txt.Txt, " "))
+			}
+		case dns.TypeNS:
+			if ns, ok := rr.(*dns.NS); ok {
+				out = append(out, ns.Ns)
+			}
+		}
+	}
+	return out
+}
+
+// systemResolver queries the resolvers listed in /etc/resolv.conf, trying
+// each in order the same way the OS stub resolver would.
+type systemResolver struct {
+	upstreams []*upstreamResolver
+}
+
+func newSystemResolver() (*systemResolver, error) {
+	cfg, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil {
+		return nil, fmt.Errorf("read /etc/resolv.conf: %w", err)
+	}
+
+	var upstreams []*upstreamResolver
+	for _, server := range cfg.Servers {
+		u, err := newUpstreamResolver(server + ":" + cfg.Port)
+		if err != nil {
+			continue
+		}
+		u.name = "system(" + server + ")"
+		upstreams = append(upstreams, u)
+	}
+	if len(upstreams) == 0 {
+		return nil, fmt.Errorf("no usable nameservers in /etc/resolv.conf")
+	}
+	return &systemResolver{upstreams: upstreams}, nil
+}
+
+func (r *systemResolver) Name() string {
+	return "system"
+}
+
+func (r *systemResolver) Resolve(hostname string, qtype uint16) ([]string, time.Duration, error) {
+	var lastErr error
+	for _, u := range r.upstreams {
+		ips, rtt, err := u.Resolve(hostname, qtype)
+		if err == nil {
+			return ips, rtt, nil
+		}
+		lastErr = err
+	}
+	return nil, 0, fmt.Errorf("system: all nameservers failed: %w", lastErr)
+}
+
+// digResolver shells out to the `dig` binary, kept for compatibility with
+// environments where a native lookup isn't desired.
+type digResolver struct{}
+
+func (digResolver) Name() string {
+	return "dig"
+}
+
+func (digResolver) Resolve(hostname string, qtype uint16) ([]string, time.Duration, error) {
+	return resolveHostnameWithDig(hostname)
+}
+
+// resolversFromEnv builds the set of resolvers to probe in parallel from
+// DNS_RESOLVERS (comma-separated upstream specs) and DNS_USE_DIG, falling
+// back to the system resolver when neither is set.
+func resolversFromEnv() []Resolver {
+	if useDig := os.Getenv("DNS_USE_DIG"); useDig != "" {
+		if ok, _ := strconv.ParseBool(useDig); ok {
+			return []Resolver{digResolver{}}
+		}
+	}
+
+	if spec := os.Getenv("DNS_RESOLVERS"); spec != "" {
+		var resolvers []Resolver
+		for _, s := range strings.Split(spec, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			u, err := newUpstreamResolver(s)
+			if err != nil {
+				fmt.Printf("⚠️  Skipping resolver %q: %v\n", s, err)
+				continue
+			}
+			resolvers = append(resolvers, u)
+		}
+		if len(resolvers) > 0 {
+			return resolvers
+		}
+	}
+
+	sys, err := newSystemResolver()
+	if err != nil {
+		fmt.Printf("⚠️  Falling back to dig: %v\n", err)
+		return []Resolver{digResolver{}}
+	}
+	return []Resolver{sys}
+}
+
+// probeResult is one resolver's outcome from a single probe round.
+type probeResult struct {
+	Resolver string
+	IPs      []string
+	Duration time.Duration
+	Err      error
+}
+
+// probeAll queries every configured resolver in parallel so that
+// failures and latency can be attributed per-resolver.
+func probeAll(resolvers []Resolver, hostname string, qtype uint16) []probeResult {
+	results := make([]probeResult, len(resolvers))
+	done := make(chan int, len(resolvers))
+
+	for i, r := range resolvers {
+		go func(i int, r Resolver) {
+			ips, d, err := r.Resolve(hostname, qtype)
+			results[i] = probeResult{Resolver: r.Name(), IPs: ips, Duration: d, Err: err}
+			done <- i
+		}(i, r)
+	}
+	for range resolvers {
+		<-done
+	}
+	return results
+}
+
+var dohHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+// dohExchange POSTs a packed DNS query to a DoH endpoint and returns the
+// packed response bytes. Split out as a var so tests can stub the network.
+var dohExchange = func(endpoint string, packed []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(packed))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := dohHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
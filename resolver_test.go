@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestNewUpstreamResolverParsesSpecs(t *testing.T) {
+	cases := []struct {
+		spec        string
+		wantNetwork string
+		wantAddr    string
+	}{
+		{"8.8.8.8:53", "udp", "8.8.8.8:53"},
+		{"8.8.8.8", "udp", "8.8.8.8:53"}, // no port -> default :53 added
+		{"udp://1.1.1.1:53", "udp", "1.1.1.1:53"},
+		{"tcp://1.1.1.1:53", "tcp", "1.1.1.1:53"},
+		{"tls://1.1.1.1:853", "tcp-tls", "1.1.1.1:853"},
+		{"https://dns.google/dns-query", "https", "https://dns.google/dns-query"},
+	}
+	for _, c := range cases {
+		t.Run(c.spec, func(t *testing.T) {
+			r, err := newUpstreamResolver(c.spec)
+			if err != nil {
+				t.Fatalf("newUpstreamResolver(%q): %v", c.spec, err)
+			}
+			if r.network != c.wantNetwork {
+				t.Errorf("network = %q, want %q", r.network, c.wantNetwork)
+			}
+			if r.addr != c.wantAddr {
+				t.Errorf("addr = %q, want %q", r.addr, c.wantAddr)
+			}
+		})
+	}
+}
+
+func TestNewUpstreamResolverRejectsUnsupportedScheme(t *testing.T) {
+	if _, err := newUpstreamResolver("ftp://1.1.1.1:53"); err == nil {
+		t.Fatal("expected an error for an unsupported scheme, got nil")
+	}
+}
+
+func TestAnswersToStrings(t *testing.T) {
+	cases := []struct {
+		name  string
+		qtype uint16
+		rr    dns.RR
+		want  string
+	}{
+		{"A", dns.TypeA, mustRR(t, "example.com. 300 IN A 93.184.216.34"), "93.184.216.34"},
+		{"AAAA", dns.TypeAAAA, mustRR(t, "example.com. 300 IN AAAA 2606:2800:220:1:248:1893:25c8:1946"), "2606:2800:220:1:248:1893:25c8:1946"},
+		{"CNAME", dns.TypeCNAME, mustRR(t, "www.example.com. 300 IN CNAME example.com."), "example.com."},
+		{"MX", dns.TypeMX, mustRR(t, "example.com. 300 IN MX 10 mail.example.com."), "10 mail.example.com."},
+		{"TXT", dns.TypeTXT, mustRR(t, `example.com. 300 IN TXT "v=spf1 -all"`), "v=spf1 -all"},
+		{"NS", dns.TypeNS, mustRR(t, "example.com. 300 IN NS ns1.example.com."), "ns1.example.com."},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := answersToStrings([]dns.RR{c.rr}, c.qtype)
+			if len(got) != 1 || got[0] != c.want {
+				t.Errorf("answersToStrings() = %v, want [%q]", got, c.want)
+			}
+		})
+	}
+}
+
+func TestAnswersToStringsSkipsMismatchedRecordType(t *testing.T) {
+	rr := mustRR(t, "example.com. 300 IN A 93.184.216.34")
+	got := answersToStrings([]dns.RR{rr}, dns.TypeMX)
+	if len(got) != 0 {
+		t.Errorf("answersToStrings() = %v, want none for a qtype that doesn't match the record", got)
+	}
+}
+
+func mustRR(t *testing.T, s string) dns.RR {
+	t.Helper()
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatalf("dns.NewRR(%q): %v", s, err)
+	}
+	return rr
+}
+
+func TestRecordTypeFromEnv(t *testing.T) {
+	cases := []struct {
+		env  string
+		want uint16
+	}{
+		{"", dns.TypeA},
+		{"a", dns.TypeA},
+		{"AAAA", dns.TypeAAAA},
+		{"txt", dns.TypeTXT},
+		{"bogus", dns.TypeA},
+	}
+	for _, c := range cases {
+		t.Run(c.env, func(t *testing.T) {
+			t.Setenv("DNS_RECORD_TYPE", c.env)
+			if got := recordTypeFromEnv(); got != c.want {
+				t.Errorf("recordTypeFromEnv() with DNS_RECORD_TYPE=%q = %v, want %v", c.env, got, c.want)
+			}
+		})
+	}
+}
+
+func TestResolversFromEnvUseDig(t *testing.T) {
+	t.Setenv("DNS_USE_DIG", "true")
+	resolvers := resolversFromEnv()
+	if len(resolvers) != 1 || resolvers[0].Name() != "dig" {
+		t.Fatalf("resolversFromEnv() with DNS_USE_DIG=true = %v, want [dig]", resolvers)
+	}
+}
+
+func TestResolversFromEnvParsesResolversList(t *testing.T) {
+	t.Setenv("DNS_RESOLVERS", "8.8.8.8:53, 1.1.1.1:53")
+	resolvers := resolversFromEnv()
+	if len(resolvers) != 2 {
+		t.Fatalf("resolversFromEnv() = %d resolvers, want 2", len(resolvers))
+	}
+	if resolvers[0].Name() != "8.8.8.8:53" || resolvers[1].Name() != "1.1.1.1:53" {
+		t.Errorf("resolversFromEnv() = %v %v, want names matching each spec", resolvers[0].Name(), resolvers[1].Name())
+	}
+}
+
+func TestResolversFromEnvSkipsUnparseableSpecs(t *testing.T) {
+	t.Setenv("DNS_RESOLVERS", "ftp://nope, 8.8.8.8:53")
+	resolvers := resolversFromEnv()
+	if len(resolvers) != 1 || resolvers[0].Name() != "8.8.8.8:53" {
+		t.Fatalf("resolversFromEnv() = %v, want only the one parseable spec", resolvers)
+	}
+}
+
+// withStubbedDoH temporarily replaces dohExchange for the duration of the test.
+func withStubbedDoH(t *testing.T, stub func(endpoint string, packed []byte) ([]byte, error)) {
+	t.Helper()
+	orig := dohExchange
+	dohExchange = stub
+	t.Cleanup(func() { dohExchange = orig })
+}
+
+func packedResponse(t *testing.T, rcode int, answer dns.RR) []byte {
+	t.Helper()
+	msg := new(dns.Msg)
+	msg.Rcode = rcode
+	if answer != nil {
+		msg.Answer = []dns.RR{answer}
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("pack stub response: %v", err)
+	}
+	return packed
+}
+
+func TestResolveDoHSuccess(t *testing.T) {
+	rr := mustRR(t, "example.com. 300 IN A 93.184.216.34")
+	withStubbedDoH(t, func(endpoint string, packed []byte) ([]byte, error) {
+		return packedResponse(t, dns.RcodeSuccess, rr), nil
+	})
+
+	ips, _, err := resolveDoH("https://dns.example/dns-query", "example.com", dns.TypeA)
+	if err != nil {
+		t.Fatalf("resolveDoH: %v", err)
+	}
+	if len(ips) != 1 || ips[0] != "93.184.216.34" {
+		t.Errorf("resolveDoH() ips = %v, want [93.184.216.34]", ips)
+	}
+}
+
+func TestResolveDoHTransportError(t *testing.T) {
+	withStubbedDoH(t, func(endpoint string, packed []byte) ([]byte, error) {
+		return nil, errors.New("unexpected status 503 Service Unavailable")
+	})
+
+	_, _, err := resolveDoH("https://dns.example/dns-query", "example.com", dns.TypeA)
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("resolveDoH() err = %v, want it to wrap the transport error", err)
+	}
+}
+
+func TestResolveDoHBadRcode(t *testing.T) {
+	withStubbedDoH(t, func(endpoint string, packed []byte) ([]byte, error) {
+		return packedResponse(t, dns.RcodeServerFailure, nil), nil
+	})
+
+	_, _, err := resolveDoH("https://dns.example/dns-query", "example.com", dns.TypeA)
+	if err == nil || !strings.Contains(err.Error(), "SERVFAIL") {
+		t.Fatalf("resolveDoH() err = %v, want a bad rcode error mentioning SERVFAIL", err)
+	}
+}
+
+func TestResolveDoHNoRecords(t *testing.T) {
+	withStubbedDoH(t, func(endpoint string, packed []byte) ([]byte, error) {
+		return packedResponse(t, dns.RcodeSuccess, nil), nil
+	})
+
+	_, _, err := resolveDoH("https://dns.example/dns-query", "example.com", dns.TypeA)
+	if err == nil {
+		t.Fatal("resolveDoH() with no answers: want an error, got nil")
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recoveryBoostRounds is how many probes after a slow response run at half
+// the normal interval, to catch the recovery window quickly.
+const recoveryBoostRounds = 3
+
+// maxBackoffShift caps how many times consecutive failures double the
+// interval, so backoff can't run away past maxInterval anyway but also
+// doesn't overflow for pathological failure streaks.
+const maxBackoffShift = 10
+
+// Scheduler paces probes with jitter to avoid synchronized bursts across
+// multiple monitors, exponential backoff on consecutive failures, and a
+// brief speed-up after a slow response to catch the recovery window. It
+// exposes a Ticker-style channel (C) so the probe loop, and tests, can
+// drive it the same way they'd drive a *time.Ticker.
+type Scheduler struct {
+	mu sync.Mutex
+
+	base        time.Duration
+	jitterFrac  float64
+	maxInterval time.Duration
+	maxQPS      float64
+
+	consecutiveFailures int
+	boostRemaining      int
+
+	C      chan time.Time
+	stopCh chan struct{}
+}
+
+// NewScheduler builds a Scheduler. jitterFrac is the +/- fraction applied
+// to every interval (e.g. 0.2 for +/-20%); maxInterval caps backoff.
+func NewScheduler(base time.Duration, jitterFrac float64, maxInterval time.Duration, maxQPS float64) *Scheduler {
+	return &Scheduler{
+		base:        base,
+		jitterFrac:  jitterFrac,
+		maxInterval: maxInterval,
+		maxQPS:      maxQPS,
+		C:           make(chan time.Time, 1),
+		stopCh:      make(chan struct{}),
+	}
+}
+
+// schedulerFromEnv builds a Scheduler from DNS_INTERVAL, DNS_JITTER_PCT,
+// DNS_MAX_BACKOFF, and DNS_MAX_QPS, falling back to the historical 50ms
+// interval with no jitter or QPS cap. Failure backoff is always on; unless
+// DNS_MAX_BACKOFF is set it is capped at 30s.
+func schedulerFromEnv() *Scheduler {
+	base := envDuration("DNS_INTERVAL", interval)
+	jitter := envFloat("DNS_JITTER_PCT", 0) / 100
+	maxBackoff := envDuration("DNS_MAX_BACKOFF", 30*time.Second)
+	maxQPS := envFloat("DNS_MAX_QPS", 0)
+	return NewScheduler(base, jitter, maxBackoff, maxQPS)
+}
+
+// Run ticks C on an adaptive schedule until Stop is called. Meant to be
+// started in its own goroutine.
+func (s *Scheduler) Run() {
+	for {
+		d := s.nextInterval()
+		timer := time.NewTimer(d)
+		select {
+		case t := <-timer.C:
+			select {
+			case s.C <- t:
+			case <-s.stopCh:
+				return
+			}
+		case <-s.stopCh:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// Stop halts Run and closes C's producer side.
+func (s *Scheduler) Stop() {
+	close(s.stopCh)
+}
+
+// Observe feeds the outcome of the most recent probe back into the
+// scheduler so the next interval can back off on failure or speed up
+// after a slow response.
+func (s *Scheduler) Observe(err error, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.consecutiveFailures++
+		return
+	}
+
+	s.consecutiveFailures = 0
+	if duration > slowResponseDuration {
+		s.boostRemaining = recoveryBoostRounds
+	} else if s.boostRemaining > 0 {
+		s.boostRemaining--
+	}
+}
+
+// nextInterval computes the next interval from the base, any QPS floor,
+// failure backoff, or post-slow-response boost, then applies jitter.
+func (s *Scheduler) nextInterval() time.Duration {
+	s.mu.Lock()
+	failures := s.consecutiveFailures
+	boosted := s.boostRemaining > 0
+	s.mu.Unlock()
+
+	d := s.base
+	if s.maxQPS > 0 {
+		if floor := time.Duration(float64(time.Second) / s.maxQPS); d < floor {
+			d = floor
+		}
+	}
+
+	switch {
+	case failures > 0:
+		shift := failures
+		if shift > maxBackoffShift {
+			shift = maxBackoffShift
+		}
+		d *= 1 << uint(shift)
+		if d > s.maxInterval {
+			d = s.maxInterval
+		}
+	case boosted:
+		d /= 2
+	}
+
+	return applyJitter(d, s.jitterFrac)
+}
+
+// applyJitter scales d by a uniformly random +/- frac fraction.
+func applyJitter(d time.Duration, frac float64) time.Duration {
+	if frac <= 0 {
+		return d
+	}
+	delta := (rand.Float64()*2 - 1) * frac
+	jittered := time.Duration(float64(d) * (1 + delta))
+	if jittered < 0 {
+		return 0
+	}
+	return jittered
+}
+
+// loadShedder skips probes when the host's load average is too high to
+// avoid the monitor itself becoming the reason for slow DNS, mirroring the
+// /proc/loadavg sampling gopsutil does on Linux.
+type loadShedder struct {
+	maxLoad float64 // 0 disables load shedding
+}
+
+func loadShedderFromEnv() *loadShedder {
+	return &loadShedder{maxLoad: envFloat("DNS_MAX_LOAD", 0)}
+}
+
+// ShouldSkip reports whether the current 1-minute load average exceeds the
+// configured threshold. A read failure (e.g. non-Linux host) never skips.
+func (l *loadShedder) ShouldSkip() bool {
+	if l.maxLoad <= 0 {
+		return false
+	}
+	load, err := readLoadAverage()
+	if err != nil {
+		return false
+	}
+	return load > l.maxLoad
+}
+
+func readLoadAverage() (float64, error) {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0, fmt.Errorf("read /proc/loadavg: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("unexpected /proc/loadavg format: %q", data)
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
+
+func envFloat(key string, fallback float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
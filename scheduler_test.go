@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedulerBackoffDoublesAndCaps(t *testing.T) {
+	s := NewScheduler(10*time.Millisecond, 0, 100*time.Millisecond, 0)
+
+	want := []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 80 * time.Millisecond, 100 * time.Millisecond}
+	for _, w := range want {
+		s.Observe(errors.New("probe failed"), 0)
+		if got := s.nextInterval(); got != w {
+			t.Errorf("nextInterval() after failure = %v, want %v (capped at maxInterval)", got, w)
+		}
+	}
+
+	s.Observe(nil, time.Millisecond)
+	if got := s.nextInterval(); got != s.base {
+		t.Errorf("nextInterval() after a success = %v, want base %v", got, s.base)
+	}
+}
+
+func TestSchedulerBoostsAfterSlowResponse(t *testing.T) {
+	s := NewScheduler(100*time.Millisecond, 0, time.Second, 0)
+
+	s.Observe(nil, slowResponseDuration+time.Millisecond)
+	for i := 0; i < recoveryBoostRounds; i++ {
+		if got, want := s.nextInterval(), s.base/2; got != want {
+			t.Errorf("round %d: nextInterval() = %v, want boosted %v", i, got, want)
+		}
+		s.Observe(nil, time.Millisecond) // fast response, consumes one boost round
+	}
+
+	if got := s.nextInterval(); got != s.base {
+		t.Errorf("nextInterval() after boost rounds exhausted = %v, want base %v", got, s.base)
+	}
+}
+
+func TestSchedulerMaxQPSFloor(t *testing.T) {
+	s := NewScheduler(time.Millisecond, 0, time.Second, 10) // 10 QPS -> 100ms floor
+	want := 100 * time.Millisecond
+	if got := s.nextInterval(); got != want {
+		t.Errorf("nextInterval() = %v, want maxQPS floor %v", got, want)
+	}
+}
+
+func TestApplyJitterNoJitterReturnsUnchanged(t *testing.T) {
+	d := 50 * time.Millisecond
+	if got := applyJitter(d, 0); got != d {
+		t.Errorf("applyJitter(d, 0) = %v, want %v unchanged", got, d)
+	}
+}
+
+func TestApplyJitterWithinBounds(t *testing.T) {
+	d := 100 * time.Millisecond
+	frac := 0.2
+	for i := 0; i < 100; i++ {
+		got := applyJitter(d, frac)
+		lo := time.Duration(float64(d) * (1 - frac))
+		hi := time.Duration(float64(d) * (1 + frac))
+		if got < lo || got > hi {
+			t.Fatalf("applyJitter(%v, %v) = %v, want within [%v, %v]", d, frac, got, lo, hi)
+		}
+	}
+}
+
+// TestSchedulerRunDrivesC exercises the Ticker-style channel end to end:
+// Run should push onto C until Stop is called, and never block forever.
+func TestSchedulerRunDrivesC(t *testing.T) {
+	s := NewScheduler(time.Millisecond, 0, 10*time.Millisecond, 0)
+	go s.Run()
+	defer s.Stop()
+
+	select {
+	case <-s.C:
+	case <-time.After(time.Second):
+		t.Fatal("Run() never sent on C within 1s")
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultCompression bounds how many centroids a percentileSketch keeps.
+// Higher values trade memory for accuracy; 100 keeps centroid count in the
+// low hundreds even after millions of samples.
+const defaultCompression = 100.0
+
+// centroid is one (mean, weight) pair in a t-digest.
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// percentileSketch is a t-digest (Dunning): an online quantile sketch whose
+// per-sample cost depends on k, the bounded number of centroids, instead of
+// the O(N log N) full sort the old printPercentiles did on every tick. The
+// nearest centroid is found by binary search, but the rank sum and the
+// sorted insert are linear, so each Add is O(k), not O(log k). Not safe for concurrent use — callers needing that
+// wrap it (see minuteSketches).
+type percentileSketch struct {
+	compression float64
+	centroids   []centroid // kept sorted by mean
+	count       float64    // total weight across all centroids
+}
+
+func newPercentileSketch() *percentileSketch {
+	return &percentileSketch{compression: defaultCompression}
+}
+
+// Add folds one sample into the sketch, merging it into the nearest
+// centroid if that centroid has room under the t-digest size bound, or
+// inserting a new singleton centroid otherwise.
+func (s *percentileSketch) Add(d time.Duration) {
+	s.addWeighted(float64(d.Microseconds()), 1)
+}
+
+func (s *percentileSketch) addWeighted(mean, weight float64) {
+	if len(s.centroids) == 0 {
+		s.centroids = append(s.centroids, centroid{mean: mean, weight: weight})
+		s.count = weight
+		return
+	}
+
+	idx := s.nearest(mean)
+	c := s.centroids[idx]
+
+	rank := s.cumulativeWeight(idx) + c.weight/2
+	q := rank / s.count
+	bound := 4 * s.count * q * (1 - q) / s.compression
+
+	if c.weight+weight <= bound {
+		newWeight := c.weight + weight
+		c.mean += (mean - c.mean) * weight / newWeight
+		c.weight = newWeight
+		s.centroids[idx] = c
+		s.count += weight
+		return
+	}
+
+	s.insert(centroid{mean: mean, weight: weight})
+	s.count += weight
+}
+
+// nearest returns the index of the centroid whose mean is closest to x.
+func (s *percentileSketch) nearest(x float64) int {
+	i := sort.Search(len(s.centroids), func(i int) bool {
+		return s.centroids[i].mean >= x
+	})
+	if i == 0 {
+		return 0
+	}
+	if i == len(s.centroids) {
+		return len(s.centroids) - 1
+	}
+	if x-s.centroids[i-1].mean <= s.centroids[i].mean-x {
+		return i - 1
+	}
+	return i
+}
+
+// cumulativeWeight returns the total weight of every centroid before idx.
+func (s *percentileSketch) cumulativeWeight(idx int) float64 {
+	var sum float64
+	for i := 0; i < idx; i++ {
+		sum += s.centroids[i].weight
+	}
+	return sum
+}
+
+// insert keeps centroids sorted by mean while adding a new one.
+func (s *percentileSketch) insert(c centroid) {
+	i := sort.Search(len(s.centroids), func(i int) bool {
+		return s.centroids[i].mean >= c.mean
+	})
+	s.centroids = append(s.centroids, centroid{})
+	copy(s.centroids[i+1:], s.centroids[i:])
+	s.centroids[i] = c
+}
+
+// Quantile returns the estimated value at the given rank (0-1). It walks
+// centroids accumulating weight until it finds the one straddling the
+// target rank, then interpolates between that centroid's mean and its
+// neighbor's based on how far into the span the target rank falls.
+func (s *percentileSketch) Quantile(q float64) time.Duration {
+	if s.count == 0 || len(s.centroids) == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return microsToDuration(s.centroids[0].mean)
+	}
+	if q >= 1 {
+		return microsToDuration(s.centroids[len(s.centroids)-1].mean)
+	}
+
+	target := q * s.count
+	var cum float64
+	for i, c := range s.centroids {
+		next := cum + c.weight
+		if target <= next {
+			if i == len(s.centroids)-1 {
+				return microsToDuration(c.mean)
+			}
+			nbr := s.centroids[i+1]
+			curMid := cum + c.weight/2
+			nbrMid := next + nbr.weight/2
+			if nbrMid == curMid {
+				return microsToDuration(c.mean)
+			}
+			frac := (target - curMid) / (nbrMid - curMid)
+			return microsToDuration(c.mean + frac*(nbr.mean-c.mean))
+		}
+		cum = next
+	}
+	return microsToDuration(s.centroids[len(s.centroids)-1].mean)
+}
+
+func microsToDuration(micros float64) time.Duration {
+	return time.Duration(micros * float64(time.Microsecond))
+}
+
+// Merge folds every centroid of other into s, preserving total weight.
+// other is left untouched.
+func (s *percentileSketch) Merge(other *percentileSketch) {
+	for _, c := range other.centroids {
+		s.addWeighted(c.mean, c.weight)
+	}
+}
+
+// Reset clears the sketch back to empty.
+func (s *percentileSketch) Reset() {
+	s.centroids = nil
+	s.count = 0
+}
+
+// minuteSketches maintains a sliding 5-minute window of percentile data as
+// five per-minute t-digests, rotated on the minute, plus one sketch that
+// never resets for lifetime percentiles.
+type minuteSketches struct {
+	mu sync.Mutex
+
+	slots      [5]*percentileSketch
+	slotMinute [5]int64 // unix-minute each slot was last written for
+
+	lifetime *percentileSketch
+}
+
+func newMinuteSketches() *minuteSketches {
+	m := &minuteSketches{lifetime: newPercentileSketch()}
+	for i := range m.slots {
+		m.slots[i] = newPercentileSketch()
+		m.slotMinute[i] = -1
+	}
+	return m
+}
+
+// Add records d into the slot for the current minute (resetting it first
+// if it's being reused from 5 minutes ago) and into the lifetime sketch.
+func (m *minuteSketches) Add(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	minute := time.Now().Unix() / 60
+	slot := int(minute % int64(len(m.slots)))
+	if m.slotMinute[slot] != minute {
+		m.slots[slot].Reset()
+		m.slotMinute[slot] = minute
+	}
+	m.slots[slot].Add(d)
+	m.lifetime.Add(d)
+}
+
+// WindowQuantile merges the slots still within the trailing 5 minutes and
+// returns their combined estimate for q.
+func (m *minuteSketches) WindowQuantile(q float64) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	merged := newPercentileSketch()
+	minute := time.Now().Unix() / 60
+	for i, s := range m.slots {
+		if minute-m.slotMinute[i] < int64(len(m.slots)) {
+			merged.Merge(s)
+		}
+	}
+	return merged.Quantile(q)
+}
+
+// LifetimeQuantile returns q over every sample ever added.
+func (m *minuteSketches) LifetimeQuantile(q float64) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lifetime.Quantile(q)
+}
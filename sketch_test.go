@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// withinPct reports whether got is within pct fraction of want (e.g. 0.05
+// for +/-5%), used because a t-digest is an approximation, not an exact
+// quantile.
+func withinPct(got, want time.Duration, pct float64) bool {
+	if want == 0 {
+		return got == 0
+	}
+	diff := math.Abs(float64(got - want))
+	return diff/float64(want) <= pct
+}
+
+func TestPercentileSketchQuantilesOnUniformData(t *testing.T) {
+	s := newPercentileSketch()
+	for i := 1; i <= 1000; i++ {
+		s.Add(time.Duration(i) * time.Millisecond)
+	}
+
+	cases := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0.5, 500 * time.Millisecond},
+		{0.9, 900 * time.Millisecond},
+		{0.99, 990 * time.Millisecond},
+	}
+	for _, c := range cases {
+		got := s.Quantile(c.q)
+		if !withinPct(got, c.want, 0.05) {
+			t.Errorf("Quantile(%v) = %v, want ~%v (+/-5%%)", c.q, got, c.want)
+		}
+	}
+}
+
+func TestPercentileSketchEmpty(t *testing.T) {
+	s := newPercentileSketch()
+	if got := s.Quantile(0.5); got != 0 {
+		t.Errorf("Quantile on empty sketch = %v, want 0", got)
+	}
+}
+
+func TestPercentileSketchReset(t *testing.T) {
+	s := newPercentileSketch()
+	for i := 1; i <= 100; i++ {
+		s.Add(time.Duration(i) * time.Millisecond)
+	}
+	s.Reset()
+	if got := s.Quantile(0.5); got != 0 {
+		t.Errorf("Quantile after Reset = %v, want 0", got)
+	}
+	if len(s.centroids) != 0 {
+		t.Errorf("Reset left %d centroids, want 0", len(s.centroids))
+	}
+}
+
+func TestPercentileSketchMerge(t *testing.T) {
+	a := newPercentileSketch()
+	for i := 1; i <= 500; i++ {
+		a.Add(time.Duration(i) * time.Millisecond)
+	}
+	b := newPercentileSketch()
+	for i := 501; i <= 1000; i++ {
+		b.Add(time.Duration(i) * time.Millisecond)
+	}
+
+	a.Merge(b)
+
+	if got, want := a.Quantile(0.5), 500*time.Millisecond; !withinPct(got, want, 0.05) {
+		t.Errorf("merged Quantile(0.5) = %v, want ~%v", got, want)
+	}
+	if got, want := a.Quantile(0.99), 990*time.Millisecond; !withinPct(got, want, 0.05) {
+		t.Errorf("merged Quantile(0.99) = %v, want ~%v", got, want)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// histogramBounds are the upper bounds (in seconds) of the
+// dns_probe_duration_seconds Prometheus histogram buckets, chosen to cover
+// typical DNS RTTs up to the slowResponseDuration threshold and beyond.
+var histogramBounds = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Stats holds every counter and bounded history the probe loop updates and
+// both the terminal UI and the HTTP API read. All access goes through its
+// sync.RWMutex so the probe loop (writer) and HTTP handlers (readers) never
+// race on the shared state.
+type Stats struct {
+	mu sync.RWMutex
+
+	successCount    int
+	slowCount       int
+	failureCount    int
+	lastResult      string
+	lastDuration    time.Duration
+	lastResolvedIPs []string
+	lastPerResolver []probeResult
+	lastCheckedAt   time.Time
+	lastSuccessAt   time.Time
+
+	resultHistory []queryResult
+	lastDurations []time.Duration
+
+	bucketCounts []uint64 // len(histogramBounds)+1, +1 for the +Inf bucket
+	sumSeconds   float64
+
+	sketches *minuteSketches
+
+	startTime time.Time
+}
+
+func newStats() *Stats {
+	return &Stats{
+		startTime:    time.Now(),
+		sketches:     newMinuteSketches(),
+		bucketCounts: make([]uint64, len(histogramBounds)+1),
+	}
+}
+
+// Record folds one probe round's results into the stats, using results[0]
+// (the primary resolver) to drive the headline counters, and returns the
+// querylog entry for that probe so the caller can persist it.
+func (s *Stats) Record(now time.Time, results []probeResult) querylogEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	primary := results[0]
+	entry := querylogEntry{Timestamp: now, IPs: primary.IPs, Duration: primary.Duration}
+
+	s.lastCheckedAt = now
+	s.lastPerResolver = results
+
+	switch {
+	case primary.Err != nil:
+		s.failureCount++
+		s.lastResult = fmt.Sprintf("❌ FAIL (%v)", primary.Err)
+		s.lastResolvedIPs = nil
+		entry.Result = "fail"
+		entry.Error = primary.Err.Error()
+	case primary.Duration > slowResponseDuration:
+		s.slowCount++
+		s.lastResult = "🐢 SLOW"
+		s.lastResolvedIPs = primary.IPs
+		s.lastSuccessAt = now
+		entry.Result = "slow"
+	default:
+		s.successCount++
+		s.lastResult = "✅ SUCCESS"
+		s.lastResolvedIPs = primary.IPs
+		s.lastSuccessAt = now
+		entry.Result = "ok"
+	}
+	s.lastDuration = primary.Duration
+
+	s.resultHistory = append(s.resultHistory, queryResult{Timestamp: now, Duration: primary.Duration})
+	s.cleanupOldResultsLocked()
+	s.updateLastDurationsLocked(primary.Duration)
+	s.observeHistogramLocked(primary.Duration)
+	s.sketches.Add(primary.Duration)
+
+	return entry
+}
+
+// Percentiles returns the requested quantiles (each 0-100) computed from
+// the t-digest sketches instead of sorting resultHistory, so rendering cost
+// depends on the bounded centroid count, not on how many samples have been
+// seen.
+func (s *Stats) Percentiles(quantiles []float64, window bool) map[float64]time.Duration {
+	out := make(map[float64]time.Duration, len(quantiles))
+	for _, q := range quantiles {
+		if window {
+			out[q] = s.sketches.WindowQuantile(q / 100)
+		} else {
+			out[q] = s.sketches.LifetimeQuantile(q / 100)
+		}
+	}
+	return out
+}
+
+func (s *Stats) cleanupOldResultsLocked() {
+	if len(s.resultHistory) > maxRecords {
+		s.resultHistory = s.resultHistory[len(s.resultHistory)-maxRecords:]
+	}
+
+	cutoff := time.Now().Add(-maxHistoryWindow)
+	filtered := s.resultHistory[:0]
+	for _, r := range s.resultHistory {
+		if r.Timestamp.After(cutoff) {
+			filtered = append(filtered, r)
+		}
+	}
+	s.resultHistory = filtered
+}
+
+func (s *Stats) updateLastDurationsLocked(duration time.Duration) {
+	s.lastDurations = append(s.lastDurations, duration)
+	if len(s.lastDurations) > 5 {
+		s.lastDurations = s.lastDurations[1:]
+	}
+}
+
+func (s *Stats) observeHistogramLocked(duration time.Duration) {
+	seconds := duration.Seconds()
+	s.sumSeconds += seconds
+	for i, bound := range histogramBounds {
+		if seconds <= bound {
+			s.bucketCounts[i]++
+			return
+		}
+	}
+	s.bucketCounts[len(histogramBounds)]++
+}
+
+// Snapshot is a point-in-time, lock-free copy of the fields printStats and
+// the HTTP handlers need to render.
+type Snapshot struct {
+	SuccessCount    int
+	SlowCount       int
+	FailureCount    int
+	LastResult      string
+	LastDuration    time.Duration
+	LastResolvedIPs []string
+	LastPerResolver []probeResult
+	LastCheckedAt   time.Time
+	LastSuccessAt   time.Time
+	LastDurations   []time.Duration
+	ResultHistory   []queryResult
+	Uptime          time.Duration
+}
+
+func (s *Stats) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	history := make([]queryResult, len(s.resultHistory))
+	copy(history, s.resultHistory)
+	durations := make([]time.Duration, len(s.lastDurations))
+	copy(durations, s.lastDurations)
+	resolvers := make([]probeResult, len(s.lastPerResolver))
+	copy(resolvers, s.lastPerResolver)
+
+	return Snapshot{
+		SuccessCount:    s.successCount,
+		SlowCount:       s.slowCount,
+		FailureCount:    s.failureCount,
+		LastResult:      s.lastResult,
+		LastDuration:    s.lastDuration,
+		LastResolvedIPs: s.lastResolvedIPs,
+		LastPerResolver: resolvers,
+		LastCheckedAt:   s.lastCheckedAt,
+		LastSuccessAt:   s.lastSuccessAt,
+		LastDurations:   durations,
+		ResultHistory:   history,
+		Uptime:          time.Since(s.startTime).Truncate(time.Second),
+	}
+}
+
+// HistogramSnapshot is a copy of the Prometheus histogram state.
+type HistogramSnapshot struct {
+	Bounds []float64
+	Counts []uint64
+	Sum    float64
+	Total  uint64
+}
+
+func (s *Stats) Histogram() HistogramSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make([]uint64, len(s.bucketCounts))
+	var total uint64
+	for i, c := range s.bucketCounts {
+		counts[i] = c
+		total += c
+	}
+	return HistogramSnapshot{Bounds: histogramBounds, Counts: counts, Sum: s.sumSeconds, Total: total}
+}
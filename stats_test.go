@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStatsRecordSuccess(t *testing.T) {
+	s := newStats()
+	now := time.Now()
+	results := []probeResult{{Resolver: "system", IPs: []string{"1.2.3.4"}, Duration: 10 * time.Millisecond}}
+
+	entry := s.Record(now, results)
+
+	if entry.Result != "ok" {
+		t.Errorf("entry.Result = %q, want ok", entry.Result)
+	}
+	snap := s.Snapshot()
+	if snap.SuccessCount != 1 || snap.SlowCount != 0 || snap.FailureCount != 0 {
+		t.Errorf("counts = %+v, want 1 success only", snap)
+	}
+	if snap.LastResolvedIPs[0] != "1.2.3.4" {
+		t.Errorf("LastResolvedIPs = %v, want [1.2.3.4]", snap.LastResolvedIPs)
+	}
+	if snap.LastSuccessAt.IsZero() {
+		t.Error("LastSuccessAt should be set after a success")
+	}
+}
+
+func TestStatsRecordSlow(t *testing.T) {
+	s := newStats()
+	results := []probeResult{{Resolver: "system", IPs: []string{"1.2.3.4"}, Duration: slowResponseDuration + time.Second}}
+
+	entry := s.Record(time.Now(), results)
+	if entry.Result != "slow" {
+		t.Errorf("entry.Result = %q, want slow", entry.Result)
+	}
+	snap := s.Snapshot()
+	if snap.SlowCount != 1 {
+		t.Errorf("SlowCount = %d, want 1", snap.SlowCount)
+	}
+	if snap.LastSuccessAt.IsZero() {
+		t.Error("a slow response still counts as reachable, LastSuccessAt should be set")
+	}
+}
+
+func TestStatsRecordFailure(t *testing.T) {
+	s := newStats()
+	wantErr := errors.New("no route to host")
+	results := []probeResult{{Resolver: "system", Err: wantErr}}
+
+	entry := s.Record(time.Now(), results)
+	if entry.Result != "fail" || entry.Error != wantErr.Error() {
+		t.Errorf("entry = %+v, want result fail with error %q", entry, wantErr)
+	}
+	snap := s.Snapshot()
+	if snap.FailureCount != 1 {
+		t.Errorf("FailureCount = %d, want 1", snap.FailureCount)
+	}
+	if !snap.LastSuccessAt.IsZero() {
+		t.Error("LastSuccessAt should stay zero until the first success")
+	}
+	if snap.LastResolvedIPs != nil {
+		t.Errorf("LastResolvedIPs after a failure = %v, want nil", snap.LastResolvedIPs)
+	}
+}
+
+func TestStatsSnapshotIsIndependentCopy(t *testing.T) {
+	s := newStats()
+	s.Record(time.Now(), []probeResult{{Duration: time.Millisecond}})
+
+	snap := s.Snapshot()
+	snap.ResultHistory[0].Duration = time.Hour
+	snap.LastDurations[0] = time.Hour
+
+	fresh := s.Snapshot()
+	if fresh.ResultHistory[0].Duration == time.Hour {
+		t.Error("mutating a returned Snapshot's ResultHistory leaked into Stats' internal state")
+	}
+	if fresh.LastDurations[0] == time.Hour {
+		t.Error("mutating a returned Snapshot's LastDurations leaked into Stats' internal state")
+	}
+}
+
+func TestStatsHistogramBucketsAndTotal(t *testing.T) {
+	s := newStats()
+	s.Record(time.Now(), []probeResult{{Duration: 5 * time.Millisecond}})  // falls in the 0.01s bucket
+	s.Record(time.Now(), []probeResult{{Duration: 2 * time.Second}})      // falls in the 2.5s bucket
+	s.Record(time.Now(), []probeResult{{Duration: 20 * time.Second}})     // overflows into +Inf
+
+	hist := s.Histogram()
+	if hist.Total != 3 {
+		t.Fatalf("Histogram().Total = %d, want 3", hist.Total)
+	}
+	if got := hist.Counts[len(hist.Counts)-1]; got != 1 {
+		t.Errorf("+Inf bucket count = %d, want 1 (for the 20s sample)", got)
+	}
+	if hist.Sum <= 0 {
+		t.Errorf("Histogram().Sum = %v, want > 0", hist.Sum)
+	}
+}
+
+// TestStatsConcurrentAccess exercises the race this request's rationale was
+// built around: a writer goroutine calling Record while readers call
+// Snapshot/Histogram/Percentiles concurrently. Run with `go test -race`.
+func TestStatsConcurrentAccess(t *testing.T) {
+	s := newStats()
+	const rounds = 500
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			s.Record(time.Now(), []probeResult{{Duration: time.Duration(i) * time.Microsecond}})
+		}
+	}()
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				_ = s.Snapshot()
+				_ = s.Histogram()
+				_ = s.Percentiles(percentileList, true)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tuiTickInterval is how often the TUI redraws, independent of the probe
+// scheduler's own (possibly much faster or slower) adaptive interval.
+const tuiTickInterval = 200 * time.Millisecond
+
+// sparkChars renders a duration series as a one-line sparkline, lowest to
+// highest, bucketed against the series' own max.
+var sparkChars = []rune("▁▂▃▄▅▆▇█")
+
+// reconfigureKind identifies which runtimeConfig field the "h"/"u"
+// keybindings are editing.
+type reconfigureKind int
+
+const (
+	reconfigureNone reconfigureKind = iota
+	reconfigureHostname
+	reconfigureUpstream
+)
+
+// tuiModel is the bubbletea model backing the live dashboard. It only
+// reads from Stats/querylog/Scheduler/runtimeConfig — all mutation of the
+// probe inputs goes through cfg's own mutex — so no locking is needed here
+// beyond what those types already do internally.
+type tuiModel struct {
+	stats     *Stats
+	qlog      *querylog
+	scheduler *Scheduler
+	cfg       *runtimeConfig
+
+	filtering    bool
+	filter       string
+	reconfigKind reconfigureKind
+	reconfigBuf  string
+	reconfigErr  string
+
+	width, height int
+	quitting      bool
+	lastExport    string
+}
+
+func newTUIModel(stats *Stats, qlog *querylog, scheduler *Scheduler, cfg *runtimeConfig) tuiModel {
+	return tuiModel{stats: stats, qlog: qlog, scheduler: scheduler, cfg: cfg}
+}
+
+// runTUI replaces the clear+printf render loop with a real terminal UI.
+// It blocks until the user quits.
+func runTUI(stats *Stats, qlog *querylog, scheduler *Scheduler, cfg *runtimeConfig) error {
+	p := tea.NewProgram(newTUIModel(stats, qlog, scheduler, cfg), tea.WithAltScreen())
+	_, err := p.Run()
+	return err
+}
+
+type tickMsg time.Time
+
+func tickCmd() tea.Cmd {
+	return tea.Tick(tuiTickInterval, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return tickCmd()
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case tickMsg:
+		return m, tickCmd()
+
+	case tea.KeyMsg:
+		if m.filtering {
+			return m.updateFiltering(msg)
+		}
+		if m.reconfigKind != reconfigureNone {
+			return m.updateReconfiguring(msg)
+		}
+		return m.updateNormal(msg)
+	}
+	return m, nil
+}
+
+func (m tuiModel) updateFiltering(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.filtering = false
+	case tea.KeyEsc:
+		m.filtering = false
+		m.filter = ""
+	case tea.KeyBackspace:
+		if len(m.filter) > 0 {
+			m.filter = m.filter[:len(m.filter)-1]
+		}
+	default:
+		m.filter += msg.String()
+	}
+	return m, nil
+}
+
+func (m tuiModel) updateNormal(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		m.quitting = true
+		return m, tea.Quit
+	case " ":
+		m.cfg.TogglePaused()
+	case "/":
+		m.filtering = true
+	case "h":
+		m.reconfigKind = reconfigureHostname
+		m.reconfigBuf = ""
+		m.reconfigErr = ""
+	case "u":
+		m.reconfigKind = reconfigureUpstream
+		m.reconfigBuf = ""
+		m.reconfigErr = ""
+	case "e":
+		m.lastExport = m.export("json")
+	case "c":
+		m.lastExport = m.export("csv")
+	}
+	return m, nil
+}
+
+// updateReconfiguring handles keystrokes while the user is typing a new
+// hostname (h) or upstream resolver spec (u) into reconfigBuf; Enter
+// applies it to cfg, Esc discards it.
+func (m tuiModel) updateReconfiguring(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		switch m.reconfigKind {
+		case reconfigureHostname:
+			if m.reconfigBuf != "" {
+				m.cfg.SetHostname(m.reconfigBuf)
+			}
+		case reconfigureUpstream:
+			if m.reconfigBuf != "" {
+				if err := m.cfg.SetUpstream(m.reconfigBuf); err != nil {
+					m.reconfigErr = err.Error()
+					return m, nil
+				}
+			}
+		}
+		m.reconfigKind = reconfigureNone
+	case tea.KeyEsc:
+		m.reconfigKind = reconfigureNone
+	case tea.KeyBackspace:
+		if len(m.reconfigBuf) > 0 {
+			m.reconfigBuf = m.reconfigBuf[:len(m.reconfigBuf)-1]
+		}
+	default:
+		m.reconfigBuf += msg.String()
+	}
+	return m, nil
+}
+
+// export writes a snapshot to dns_snapshot_<unix>.<format> and returns the
+// path (or an error message). JSON holds the current counters plus the
+// recent query log; CSV holds the recent query log entries only.
+func (m tuiModel) export(format string) string {
+	snap := m.stats.Snapshot()
+	entries := m.qlog.Recent(0, 500)
+
+	path := fmt.Sprintf("dns_snapshot_%d.%s", time.Now().Unix(), format)
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Sprintf("export failed: %v", err)
+	}
+	defer f.Close()
+
+	switch format {
+	case "csv":
+		w := csv.NewWriter(f)
+		_ = w.Write([]string{"timestamp", "result", "duration_ns", "ips", "error"})
+		for _, e := range entries {
+			_ = w.Write([]string{
+				e.Timestamp.Format(time.RFC3339Nano),
+				e.Result,
+				strconv.FormatInt(int64(e.Duration), 10),
+				strings.Join(e.IPs, " "),
+				e.Error,
+			})
+		}
+		w.Flush()
+	default:
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(map[string]any{
+			"success_count": snap.SuccessCount,
+			"slow_count":    snap.SlowCount,
+			"failure_count": snap.FailureCount,
+			"entries":       entries,
+		})
+	}
+	return path
+}
+
+func (m tuiModel) View() string {
+	if m.quitting {
+		return ""
+	}
+
+	snap := m.stats.Snapshot()
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "📡 DNS Monitor — %s", m.cfg.Hostname())
+	if m.cfg.Paused() {
+		b.WriteString("  [PAUSED]")
+	}
+	fmt.Fprintf(&b, "   uptime %v\n\n", snap.Uptime)
+
+	fmt.Fprintf(&b, "✅ %d   🐢 %d   ❌ %d   last: %s (%v)\n\n",
+		snap.SuccessCount, snap.SlowCount, snap.FailureCount, snap.LastResult, snap.LastDuration)
+
+	b.WriteString("Sparkline (last 5 durations): ")
+	b.WriteString(sparkline(snap.LastDurations))
+	b.WriteString("\n\n")
+
+	b.WriteString("Top 5 Slowest (5m window):\n")
+	for i, r := range getTopSlowest(snap.ResultHistory) {
+		fmt.Fprintf(&b, "  %d. %s  %v\n", i+1, r.Timestamp.Format("15:04:05.000"), r.Duration)
+	}
+	b.WriteString("\n")
+
+	b.WriteString("Percentile histogram (5m window):\n")
+	for _, p := range percentileList {
+		val := m.stats.Percentiles([]float64{p}, true)[p]
+		bar := buildBar(float64(val.Milliseconds()), float64(m.stats.Percentiles([]float64{99.9}, true)[99.9].Milliseconds()))
+		fmt.Fprintf(&b, "  P%-5g %s %dms\n", p, bar, val.Milliseconds())
+	}
+	b.WriteString("\n")
+
+	b.WriteString("Query log")
+	if m.filtering || m.filter != "" {
+		fmt.Fprintf(&b, " (filter: %s)", m.filter)
+	}
+	b.WriteString(":\n")
+	for _, e := range filteredRecent(m.qlog.Recent(0, 10), m.filter) {
+		fmt.Fprintf(&b, "  %s  %-4s  %v  %v\n", e.Timestamp.Format("15:04:05.000"), e.Result, e.Duration, e.IPs)
+	}
+
+	if m.lastExport != "" {
+		fmt.Fprintf(&b, "\nExported: %s\n", m.lastExport)
+	}
+
+	switch m.reconfigKind {
+	case reconfigureHostname:
+		fmt.Fprintf(&b, "\nNew hostname: %s_\n", m.reconfigBuf)
+	case reconfigureUpstream:
+		fmt.Fprintf(&b, "\nNew upstream (e.g. 1.1.1.1:53, tls://1.1.1.1:853): %s_\n", m.reconfigBuf)
+	}
+	if m.reconfigErr != "" {
+		fmt.Fprintf(&b, "⚠️  %s\n", m.reconfigErr)
+	}
+
+	b.WriteString("\n(space: pause · h: set hostname · u: set upstream · /: filter · e: export JSON · c: export CSV · q: quit)")
+	return b.String()
+}
+
+func filteredRecent(entries []querylogEntry, filter string) []querylogEntry {
+	if filter == "" {
+		return entries
+	}
+	out := entries[:0]
+	for _, e := range entries {
+		if strings.Contains(strings.Join(e.IPs, " "), filter) || strings.Contains(e.Error, filter) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func sparkline(durations []time.Duration) string {
+	if len(durations) == 0 {
+		return "(no data yet)"
+	}
+	var max time.Duration
+	for _, d := range durations {
+		if d > max {
+			max = d
+		}
+	}
+	if max == 0 {
+		max = 1
+	}
+
+	var b strings.Builder
+	for _, d := range durations {
+		idx := int(float64(d) / float64(max) * float64(len(sparkChars)-1))
+		b.WriteRune(sparkChars[idx])
+	}
+	return b.String()
+}
+
+// plainModeRequested reports whether the terminal UI should be skipped in
+// favor of the plain printStats output — either because the user passed
+// --plain, or because stdout isn't a TTY (CI, `docker logs`).
+func plainModeRequested() bool {
+	for _, arg := range os.Args[1:] {
+		if arg == "--plain" {
+			return true
+		}
+	}
+	return !isTerminal(os.Stdout)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}